@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff
+
+import "iter"
+
+// Unit is the common shape of a translatable entry, satisfied by both a
+// v1.2 TransUnit and a v2.0 segment, so version-agnostic code that only
+// needs to read source/target pairs (e.g. IsCatalogComplete below) can
+// work against either version. Document.Validate, Document.IsComplete,
+// and the pipeline package are 1.2-specific and do not go through this
+// interface.
+type Unit interface {
+	UnitID() string
+	SourceContent() Content
+	TargetContent() Content
+}
+
+// Catalog is a translation document, regardless of XLIFF version.
+type Catalog interface {
+	// Units iterates every translatable unit across all files.
+	Units() iter.Seq[Unit]
+	SourceLang() string
+	TargetLang() string
+}
+
+// UnitID returns t.ID.
+func (t *TransUnit) UnitID() string { return t.ID }
+
+// SourceContent returns t.Source.
+func (t *TransUnit) SourceContent() Content { return t.Source }
+
+// TargetContent returns t.Target's Content.
+func (t *TransUnit) TargetContent() Content { return t.Target.Content }
+
+// Units iterates every TransUnit across all of d's files.
+func (d *Document) Units() iter.Seq[Unit] {
+	return func(yield func(Unit) bool) {
+		for fi := range d.Files {
+			for ui := range d.Files[fi].Body.TransUnits {
+				if !yield(&d.Files[fi].Body.TransUnits[ui]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SourceLang returns the source-language of d's first file. Validate
+// already requires every file in a Document to share the same source
+// and target language.
+func (d *Document) SourceLang() string {
+	if len(d.Files) == 0 {
+		return ""
+	}
+	return d.Files[0].SourceLanguage
+}
+
+// TargetLang returns the target-language of d's first file.
+func (d *Document) TargetLang() string {
+	if len(d.Files) == 0 {
+		return ""
+	}
+	return d.Files[0].TargetLanguage
+}
+
+// IsCatalogComplete reports whether every unit in c has a non-empty
+// source and target. It is the version-agnostic counterpart of
+// Document.IsComplete: unlike IsComplete, it knows nothing about XLIFF
+// 1.2 plural Cases, so a Document with plural trans-units should keep
+// using IsComplete instead.
+func IsCatalogComplete(c Catalog) bool {
+	for u := range c.Units() {
+		if u.SourceContent().IsEmpty() || u.TargetContent().IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	_ Catalog = (*Document)(nil)
+	_ Unit    = (*TransUnit)(nil)
+)
@@ -0,0 +1,25 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff_test
+
+import (
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+func Test_IsCatalogComplete(t *testing.T) {
+	doc := xliff.NewDocument("en", "de")
+	doc.AddTransUnit("hello", xliff.WithTarget("hallo"))
+
+	if !xliff.IsCatalogComplete(doc) {
+		t.Error("expected IsCatalogComplete to be true when every unit has a target")
+	}
+
+	doc.AddTransUnit("goodbye")
+	if xliff.IsCatalogComplete(doc) {
+		t.Error("expected IsCatalogComplete to be false once a unit is missing its target")
+	}
+}
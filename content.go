@@ -0,0 +1,375 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Content models the mixed content of an XLIFF <source> or <target>
+// element: plain text interleaved with the inline elements defined by
+// the XLIFF 1.2 inline-elements module (g, x, bx/ex, ph, bpt/ept, sub).
+// Unmarshalling keeps g, x, bx/ex, ph and bpt/ept as runs instead of
+// silently discarding them. It does not keep <sub> or any other
+// element, and it does not keep the inline code inside <ph> and <x>:
+// only their attributes survive, so a file that uses these does not
+// round-trip unchanged.
+//
+// The standalone placeholder elements x and ph are both represented as
+// Placeholder, and the paired elements bx/ex and bpt/ept are both
+// represented as PairedTag; this is a deliberate, documented
+// simplification that keeps the run model small while still
+// round-tripping real-world payloads.
+type Content struct {
+	Runs []Run
+}
+
+// Run is one piece of a Content's mixed content: TextRun, Placeholder,
+// Group or PairedTag.
+type Run interface {
+	isRun()
+}
+
+// TextRun is a run of plain, translatable text.
+type TextRun string
+
+func (TextRun) isRun() {}
+
+// Placeholder is a standalone inline element (<ph> or <x>) that stands
+// in for inline code a translator should not alter.
+type Placeholder struct {
+	ID    string
+	CType string
+	Equiv string
+	Disp  string
+}
+
+func (Placeholder) isRun() {}
+
+// Group is a generic inline grouping element (<g>) wrapping other runs.
+type Group struct {
+	ID       string
+	Children []Run
+}
+
+func (Group) isRun() {}
+
+// PairedTag is a paired inline element (<bpt>/<ept> or <bx>/<ex>)
+// wrapping the runs between its open and close tags. Open and Close
+// hold the literal code carried by the opening and closing tag
+// respectively. A PairedTag built by Unmarshal with only one of
+// Open/Close set represents a tag whose partner could not be found in
+// the source document; Validate reports this as UnbalancedPairedTag.
+type PairedTag struct {
+	ID       string
+	Open     string
+	Close    string
+	Children []Run
+}
+
+func (PairedTag) isRun() {}
+
+// TextContent returns a Content holding a single run of plain text s.
+func TextContent(s string) Content {
+	if s == "" {
+		return Content{}
+	}
+	return Content{Runs: []Run{TextRun(s)}}
+}
+
+// IsEmpty reports whether c carries no runs at all.
+func (c Content) IsEmpty() bool {
+	return len(c.Runs) == 0
+}
+
+// PlainText returns c's text with all inline markup stripped, for
+// callers that only care about the translatable words. Placeholders
+// contribute their Equiv text, if any, as a stand-in.
+func (c Content) PlainText() string {
+	var sb strings.Builder
+	writePlainText(&sb, c.Runs)
+	return sb.String()
+}
+
+func writePlainText(sb *strings.Builder, runs []Run) {
+	for _, r := range runs {
+		switch v := r.(type) {
+		case TextRun:
+			sb.WriteString(string(v))
+		case Placeholder:
+			sb.WriteString(v.Equiv)
+		case Group:
+			writePlainText(sb, v.Children)
+		case PairedTag:
+			writePlainText(sb, v.Children)
+		}
+	}
+}
+
+// MarshalXML implements xml.Marshaler, emitting c's runs as children of
+// start.
+func (c Content) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := marshalRuns(e, c.Runs); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func marshalRuns(e *xml.Encoder, runs []Run) error {
+	for _, r := range runs {
+		if err := marshalRun(e, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalRun(e *xml.Encoder, r Run) error {
+	switch v := r.(type) {
+	case TextRun:
+		return e.EncodeToken(xml.CharData(v))
+	case Placeholder:
+		start := xml.StartElement{Name: xml.Name{Local: "ph"}, Attr: attrs(
+			"id", v.ID, "ctype", v.CType, "equiv-text", v.Equiv, "disp", v.Disp,
+		)}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case Group:
+		start := xml.StartElement{Name: xml.Name{Local: "g"}, Attr: attrs("id", v.ID)}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := marshalRuns(e, v.Children); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case PairedTag:
+		bpt := xml.StartElement{Name: xml.Name{Local: "bpt"}, Attr: attrs("id", v.ID)}
+		if err := e.EncodeToken(bpt); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(xml.CharData(v.Open)); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(bpt.End()); err != nil {
+			return err
+		}
+		if err := marshalRuns(e, v.Children); err != nil {
+			return err
+		}
+		ept := xml.StartElement{Name: xml.Name{Local: "ept"}, Attr: attrs("id", v.ID)}
+		if err := e.EncodeToken(ept); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(xml.CharData(v.Close)); err != nil {
+			return err
+		}
+		return e.EncodeToken(ept.End())
+	}
+	return nil
+}
+
+// attrs builds an []xml.Attr from alternating name/value pairs, skipping
+// any pair whose value is empty.
+func attrs(pairs ...string) []xml.Attr {
+	var out []xml.Attr
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		out = append(out, xml.Attr{Name: xml.Name{Local: pairs[i]}, Value: pairs[i+1]})
+	}
+	return out
+}
+
+// UnmarshalXML implements xml.Unmarshaler, reading start's children into
+// runs. Stray bpt/ept elements that cannot be paired are kept, not
+// dropped, so Validate can report them as unbalanced.
+func (c *Content) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	runs, err := unmarshalRuns(d, start.Name)
+	if err != nil {
+		return err
+	}
+	c.Runs = runs
+	return nil
+}
+
+type openPair struct {
+	tag      *PairedTag
+	children *[]Run
+}
+
+func unmarshalRuns(d *xml.Decoder, end xml.Name) ([]Run, error) {
+	runs := []Run{}
+	var stack []openPair
+
+	cur := func() *[]Run {
+		if len(stack) == 0 {
+			return &runs
+		}
+		return stack[len(stack)-1].children
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			*cur() = append(*cur(), TextRun(string(t)))
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "ph", "x":
+				var ph Placeholder
+				ph.ID = attrValue(t, "id")
+				ph.CType = attrValue(t, "ctype")
+				ph.Equiv = attrValue(t, "equiv-text")
+				ph.Disp = attrValue(t, "disp")
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+				*cur() = append(*cur(), ph)
+			case "g":
+				children, err := unmarshalRuns(d, t.Name)
+				if err != nil {
+					return nil, err
+				}
+				*cur() = append(*cur(), Group{ID: attrValue(t, "id"), Children: children})
+			case "bpt", "bx":
+				code, err := readCharData(d, t.Name)
+				if err != nil {
+					return nil, err
+				}
+				pt := &PairedTag{ID: attrValue(t, "id"), Open: code}
+				children := []Run{}
+				stack = append(stack, openPair{tag: pt, children: &children})
+			case "ept", "ex":
+				code, err := readCharData(d, t.Name)
+				if err != nil {
+					return nil, err
+				}
+				if len(stack) == 0 {
+					// Orphan close tag: no matching open tag was seen.
+					runs = append(runs, PairedTag{ID: attrValue(t, "id"), Close: code})
+					continue
+				}
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				top.tag.Close = code
+				top.tag.Children = *top.children
+				*cur() = append(*cur(), *top.tag)
+			default:
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if t.Name == end {
+				// Any still-open paired tags never saw their partner.
+				for _, open := range stack {
+					open.tag.Children = *open.children
+					runs = append(runs, *open.tag)
+				}
+				return runs, nil
+			}
+		}
+	}
+}
+
+func attrValue(start xml.StartElement, local string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func readCharData(d *xml.Decoder, end xml.Name) (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			if t.Name == end {
+				return sb.String(), nil
+			}
+		}
+	}
+}
+
+// printfVerb matches a single printf-style verb, e.g. %d, %-5.2f, %[2]s.
+var printfVerb = regexp.MustCompile(`%(\[\d+\])?[-+ #0]*\d*(\.\d+)?[vTtbcdoqxXUeEfFgGsqp%]`)
+
+// PlaceholdersFromPrintf splits a printf-style format string into a
+// Content whose verbs (%s, %d, %[1]v, ...) become Placeholder runs with
+// CType "x-printf" and the verb text as Equiv, so the result can be
+// embedded in XLIFF payloads that use inline placeholder markup. The
+// literal "%%" is left as plain text.
+func PlaceholdersFromPrintf(format string) Content {
+	matches := printfVerb.FindAllStringIndex(format, -1)
+	var runs []Run
+	last := 0
+	for i, m := range matches {
+		verb := format[m[0]:m[1]]
+		if verb == "%%" {
+			continue
+		}
+		if m[0] > last {
+			runs = append(runs, TextRun(format[last:m[0]]))
+		}
+		runs = append(runs, Placeholder{
+			ID:    strconv.Itoa(i + 1),
+			CType: "x-printf",
+			Equiv: verb,
+		})
+		last = m[1]
+	}
+	if last < len(format) {
+		runs = append(runs, TextRun(format[last:]))
+	}
+	return Content{Runs: runs}
+}
+
+// PrintfFromPlaceholders reassembles a printf-style format string from a
+// Content produced by PlaceholdersFromPrintf (or any Content whose
+// Placeholder runs carry a verb in Equiv), discarding any other inline
+// markup.
+func PrintfFromPlaceholders(c Content) string {
+	var sb strings.Builder
+	var walk func([]Run)
+	walk = func(runs []Run) {
+		for _, r := range runs {
+			switch v := r.(type) {
+			case TextRun:
+				sb.WriteString(string(v))
+			case Placeholder:
+				if v.Equiv != "" {
+					sb.WriteString(v.Equiv)
+				}
+			case Group:
+				walk(v.Children)
+			case PairedTag:
+				walk(v.Children)
+			}
+		}
+	}
+	walk(c.Runs)
+	return sb.String()
+}
@@ -0,0 +1,147 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+// marshalContent marshals c as a <source> (or <target>, etc.) element
+// named elem. xml.Marshal won't do: it only honors an XMLName struct
+// tag when deriving the start element itself, and Content implements
+// xml.Marshaler only via its own MarshalXML, so a wrapper struct's
+// XMLName is never consulted. Calling MarshalXML directly with an
+// explicit start element sidesteps that.
+func marshalContent(t *testing.T, elem string, c xliff.Content) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := c.MarshalXML(enc, xml.StartElement{Name: xml.Name{Local: elem}}); err != nil {
+		t.Fatalf("MarshalXML: %v", err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	return buf.String()
+}
+
+func unmarshalContent(t *testing.T, data string) xliff.Content {
+	t.Helper()
+	var c xliff.Content
+	dec := xml.NewDecoder(strings.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		t.Fatalf("reading start token: %v", err)
+	}
+	start, ok := tok.(xml.StartElement)
+	if !ok {
+		t.Fatalf("expected start element, got %T", tok)
+	}
+	if err := c.UnmarshalXML(dec, start); err != nil {
+		t.Fatalf("UnmarshalXML: %v", err)
+	}
+	return c
+}
+
+func Test_Content_RoundTripPlainText(t *testing.T) {
+	c := unmarshalContent(t, `<source>Hello World</source>`)
+	if got := c.PlainText(); got != "Hello World" {
+		t.Errorf("PlainText() = %q, want %q", got, "Hello World")
+	}
+
+	data := marshalContent(t, "source", c)
+	if data != `<source>Hello World</source>` {
+		t.Errorf("MarshalXML() = %q", data)
+	}
+}
+
+func Test_Content_Placeholder(t *testing.T) {
+	c := unmarshalContent(t, `<source>Hello <ph id="1" ctype="x-printf" equiv-text="%s">%s</ph></source>`)
+	if len(c.Runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(c.Runs))
+	}
+	ph, ok := c.Runs[1].(xliff.Placeholder)
+	if !ok {
+		t.Fatalf("expected second run to be a Placeholder, got %T", c.Runs[1])
+	}
+	if ph.ID != "1" || ph.CType != "x-printf" || ph.Equiv != "%s" {
+		t.Errorf("unexpected Placeholder: %+v", ph)
+	}
+	if got := c.PlainText(); got != "Hello %s" {
+		t.Errorf("PlainText() = %q, want %q", got, "Hello %s")
+	}
+}
+
+func Test_Content_GroupAndPairedTag(t *testing.T) {
+	c := unmarshalContent(t, `<source><g id="1">bold <bpt id="2">&lt;i&gt;</bpt>italic<ept id="2">&lt;/i&gt;</ept></g></source>`)
+	g, ok := c.Runs[0].(xliff.Group)
+	if !ok {
+		t.Fatalf("expected a Group, got %T", c.Runs[0])
+	}
+	if g.ID != "1" {
+		t.Errorf("Group.ID = %q, want %q", g.ID, "1")
+	}
+	pt, ok := g.Children[1].(xliff.PairedTag)
+	if !ok {
+		t.Fatalf("expected a PairedTag, got %T", g.Children[1])
+	}
+	if pt.Open != "<i>" || pt.Close != "</i>" {
+		t.Errorf("unexpected PairedTag: %+v", pt)
+	}
+	if got := c.PlainText(); got != "bold italic" {
+		t.Errorf("PlainText() = %q, want %q", got, "bold italic")
+	}
+}
+
+func Test_Validate_UnbalancedPairedTag(t *testing.T) {
+	c := unmarshalContent(t, `<source><bpt id="1">&lt;b&gt;</bpt>bold</source>`)
+	doc := xliff.NewDocument("en", "de")
+	doc.Files[0].Original = "test.strings"
+	doc.Files[0].Body.TransUnits = []xliff.TransUnit{{ID: "1", Source: c, Target: xliff.Target{Content: xliff.TextContent("fett")}}}
+
+	errs := doc.Validate()
+	found := false
+	for _, e := range errs {
+		if e.Code == xliff.UnbalancedPairedTag {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected Validate() to report UnbalancedPairedTag")
+	}
+}
+
+func Test_Validate_DuplicateInlineID(t *testing.T) {
+	c := unmarshalContent(t, `<source><ph id="1">%s</ph> and <ph id="1">%d</ph></source>`)
+	doc := xliff.NewDocument("en", "de")
+	doc.Files[0].Original = "test.strings"
+	doc.Files[0].Body.TransUnits = []xliff.TransUnit{{ID: "1", Source: c, Target: xliff.Target{Content: xliff.TextContent("x")}}}
+
+	errs := doc.Validate()
+	found := false
+	for _, e := range errs {
+		if e.Code == xliff.DuplicateInlineID {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected Validate() to report DuplicateInlineID")
+	}
+}
+
+func Test_PlaceholdersFromPrintf(t *testing.T) {
+	c := xliff.PlaceholdersFromPrintf("Hello %s, you have %d messages")
+	if got := c.PlainText(); got != "Hello %s, you have %d messages" {
+		t.Errorf("PlainText() = %q", got)
+	}
+	if got := xliff.PrintfFromPlaceholders(c); got != "Hello %s, you have %d messages" {
+		t.Errorf("PrintfFromPlaceholders() = %q", got)
+	}
+}
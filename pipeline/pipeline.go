@@ -0,0 +1,318 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+// Package pipeline extracts translatable format strings from Go source
+// into an xliff.Document, in the spirit of
+// golang.org/x/text/message/pipeline. It walks a module with go/packages,
+// finds calls to configurable printer functions, and turns each distinct
+// format string into a TransUnit.
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/truesch/xliff"
+)
+
+// Config controls how Extract walks a module in search of translatable
+// strings.
+type Config struct {
+	// Dir is the directory Extract loads packages from. Defaults to the
+	// current directory.
+	Dir string
+
+	// Patterns are the go/packages load patterns passed through to
+	// packages.Load. Defaults to []string{"./..."}.
+	Patterns []string
+
+	// Funcs maps each fully qualified printer method whose format
+	// string argument should be extracted, e.g.
+	// "golang.org/x/text/message.Printer.Printf", to the zero-based
+	// index of that argument. Defaults to Printf, Sprintf and Fprintf
+	// on golang.org/x/text/message.Printer (see defaultFuncs).
+	Funcs map[string]int
+
+	SourceLanguage string
+	TargetLanguage string
+}
+
+// defaultFuncs are the printer methods Extract looks for when
+// Config.Funcs is empty, along with the zero-based index of their format
+// string argument.
+var defaultFuncs = map[string]int{
+	"golang.org/x/text/message.Printer.Printf":  0,
+	"golang.org/x/text/message.Printer.Sprintf": 0,
+	"golang.org/x/text/message.Printer.Fprintf": 1,
+}
+
+// Extract loads the Go packages described by cfg and returns an
+// xliff.Document containing one TransUnit per distinct format string
+// found in a call to one of cfg.Funcs. Call sites in the same package
+// sharing an identical format string are deduplicated, with every
+// location accumulated into the TransUnit's Note.
+func Extract(cfg Config) (*xliff.Document, error) {
+	funcIndex := defaultFuncs
+	if len(cfg.Funcs) > 0 {
+		funcIndex = cfg.Funcs
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo |
+			packages.NeedSyntax | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+		Dir: cfg.Dir,
+	}, patternsOrDefault(cfg.Patterns)...)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: loading packages: %w", err)
+	}
+
+	doc := xliff.NewDocument(cfg.SourceLanguage, cfg.TargetLanguage)
+	units := map[unitKey]*xliff.TransUnit{}
+	var order []unitKey
+
+	for _, pkg := range pkgs {
+		for _, err := range pkg.Errors {
+			return nil, fmt.Errorf("pipeline: %s: %w", pkg.ID, err)
+		}
+		for _, file := range pkg.Syntax {
+			extractFile(pkg, file, funcIndex, units, &order)
+		}
+	}
+
+	for _, key := range order {
+		doc.Files[0].Body.TransUnits = append(doc.Files[0].Body.TransUnits, *units[key])
+	}
+
+	return doc, nil
+}
+
+func patternsOrDefault(patterns []string) []string {
+	if len(patterns) > 0 {
+		return patterns
+	}
+	return []string{"./..."}
+}
+
+// unitKey deduplicates call sites by package path and format string
+// together, matching stableID's notion of identity: the same literal
+// format string in two different packages is two distinct units, not
+// one merged under whichever package go/packages happened to load
+// first.
+type unitKey struct {
+	pkgPath string
+	source  string
+}
+
+func extractFile(pkg *packages.Package, file *ast.File, funcIndex map[string]int, units map[unitKey]*xliff.TransUnit, order *[]unitKey) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		fn := calledFunc(pkg.TypesInfo, call)
+		argIdx, ok := funcIndex[fn]
+		if !ok || argIdx >= len(call.Args) {
+			return true
+		}
+
+		source, ok := constantString(pkg.TypesInfo, call.Args[argIdx])
+		if !ok {
+			return true
+		}
+
+		pos := pkg.Fset.Position(call.Pos())
+		location := fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+
+		var argTypes []string
+		for i := argIdx + 1; i < len(call.Args); i++ {
+			t := pkg.TypesInfo.TypeOf(call.Args[i])
+			argTypes = append(argTypes, fmt.Sprintf("arg%d: %s", i-argIdx, t.String()))
+		}
+
+		note := location
+		if len(argTypes) > 0 {
+			note += " (" + strings.Join(argTypes, ", ") + ")"
+		}
+		if translators := translatorsComment(pkg.Fset, file, call.Pos()); translators != "" {
+			note = translators + "\n" + note
+		}
+
+		key := unitKey{pkgPath: pkg.PkgPath, source: source}
+		if existing, ok := units[key]; ok {
+			existing.Note += "\n" + location
+			return true
+		}
+
+		tu := &xliff.TransUnit{
+			ID:     stableID(pkg.PkgPath, source),
+			Source: xliff.TextContent(source),
+			Note:   note,
+		}
+		units[key] = tu
+		*order = append(*order, key)
+
+		return true
+	})
+}
+
+// calledFunc returns the fully qualified "pkg/path.Recv.Method" (or
+// "pkg/path.Func" for a plain function) that call invokes, or "" if it
+// cannot be resolved statically.
+func calledFunc(info *types.Info, call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	selection, ok := info.Selections[sel]
+	if !ok {
+		return ""
+	}
+	fn, ok := selection.Obj().(*types.Func)
+	if !ok {
+		return ""
+	}
+	sig := fn.Type().(*types.Signature)
+	recv := sig.Recv()
+	if recv == nil {
+		return fn.FullName()
+	}
+	recvType := recv.Type()
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		recvType = ptr.Elem()
+	}
+	named, ok := recvType.(*types.Named)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s.%s", named.Obj().Pkg().Path(), named.Obj().Name(), fn.Name())
+}
+
+// constantString returns the compile-time constant string value of expr,
+// if any. Go's type checker already folds constant concatenation (e.g.
+// "a" + "b"), so no separate SSA pass is required.
+func constantString(info *types.Info, expr ast.Expr) (string, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil {
+		return "", false
+	}
+	if tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// translatorsComment returns the text of a "// TRANSLATORS:" comment on
+// the line immediately above pos, or "" if there is none. Requiring
+// adjacency (rather than just the closest preceding match anywhere
+// earlier in the file) keeps a comment attached to one call from
+// leaking onto an uncommented call further down the same function.
+func translatorsComment(fset *token.FileSet, file *ast.File, pos token.Pos) string {
+	posLine := fset.Position(pos).Line
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if fset.Position(c.Slash).Line != posLine-1 {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(text, "TRANSLATORS:") {
+				continue
+			}
+			return strings.TrimSpace(strings.TrimPrefix(text, "TRANSLATORS:"))
+		}
+	}
+	return ""
+}
+
+// stableID returns a stable, short identifier derived from pkgPath and
+// source, suitable for use as a TransUnit.ID that survives reordering of
+// the source file.
+func stableID(pkgPath, source string) string {
+	sum := sha256.Sum256([]byte(pkgPath + "\x00" + source))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// locationPattern matches the "file.go:line" call-site locations
+// extractFile records in a TransUnit's Note, one per line it was seen
+// at (see Extract).
+var locationPattern = regexp.MustCompile(`\S+\.go:\d+`)
+
+// locations returns every call-site location embedded in note.
+func locations(note string) []string {
+	return locationPattern.FindAllString(note, -1)
+}
+
+// Merge combines a freshly extracted document with an existing,
+// translated one. Units whose source text is unchanged keep their
+// existing Target; brand-new units are reported with an empty Target so
+// they can be filled in. A unit whose source text changed at a call
+// site that already existed keeps its prior Target too, but its state
+// is reset to StateNeedsReviewTranslation and the prior source/target
+// pair is kept around in a fresh AltTrans with Origin
+// "previous-version", so a translator can see exactly what changed.
+func Merge(existing *xliff.Document, fresh *xliff.Document) *xliff.Document {
+	bySource := map[string]xliff.TransUnit{}
+	byLocation := map[string]xliff.TransUnit{}
+	if existing != nil {
+		for _, file := range existing.Files {
+			for _, tu := range file.Body.TransUnits {
+				bySource[tu.Source.PlainText()] = tu
+				for _, loc := range locations(tu.Note) {
+					byLocation[loc] = tu
+				}
+			}
+		}
+	}
+
+	merged := &xliff.Document{Version: fresh.Version}
+	for _, file := range fresh.Files {
+		out := file
+		out.Body.TransUnits = make([]xliff.TransUnit, 0, len(file.Body.TransUnits))
+		for _, tu := range file.Body.TransUnits {
+			switch prev, ok := matchUnit(tu, bySource, byLocation); {
+			case !ok:
+				tu.Note += "\n[NEEDS TRANSLATION]"
+			case prev.Source.PlainText() == tu.Source.PlainText():
+				tu.Target = prev.Target
+			default:
+				tu.Target = prev.Target
+				tu.Target.State = xliff.StateNeedsReviewTranslation
+				tu.AltTrans = append(tu.AltTrans, xliff.AltTrans{
+					Origin: "previous-version",
+					Source: prev.Source,
+					Target: prev.Target.Content,
+				})
+			}
+			out.Body.TransUnits = append(out.Body.TransUnits, tu)
+		}
+		merged.Files = append(merged.Files, out)
+	}
+
+	return merged
+}
+
+// matchUnit finds the existing TransUnit tu corresponds to: an exact
+// source-text match if one exists, falling back to any existing unit
+// that was last seen at one of tu's call sites (i.e. the same logical
+// unit with its source text edited).
+func matchUnit(tu xliff.TransUnit, bySource, byLocation map[string]xliff.TransUnit) (xliff.TransUnit, bool) {
+	if prev, ok := bySource[tu.Source.PlainText()]; ok {
+		return prev, true
+	}
+	for _, loc := range locations(tu.Note) {
+		if prev, ok := byLocation[loc]; ok {
+			return prev, true
+		}
+	}
+	return xliff.TransUnit{}, false
+}
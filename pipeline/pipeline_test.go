@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+func Test_stableID(t *testing.T) {
+	a := stableID("example.com/foo", "hello %s")
+	b := stableID("example.com/foo", "hello %s")
+	if a != b {
+		t.Error("stableID is not deterministic for identical input")
+	}
+
+	c := stableID("example.com/bar", "hello %s")
+	if a == c {
+		t.Error("stableID should depend on package path")
+	}
+}
+
+func Test_Merge_PreservesUnchangedTarget(t *testing.T) {
+	existing := xliff.NewDocument("en", "de")
+	existing.AddTransUnit("hello %s", xliff.WithTarget("hallo %s"))
+
+	fresh := xliff.NewDocument("en", "de")
+	fresh.AddTransUnit("hello %s")
+	fresh.AddTransUnit("goodbye %s")
+
+	merged := Merge(existing, fresh)
+
+	units := merged.Files[0].Body.TransUnits
+	if len(units) != 2 {
+		t.Fatalf("expected 2 trans-units, got %d", len(units))
+	}
+	if units[0].Target.PlainText() != "hallo %s" {
+		t.Error("Merge did not preserve Target for an unchanged source")
+	}
+	if !units[1].Target.IsEmpty() {
+		t.Error("Merge should not invent a Target for a new source")
+	}
+}
+
+func Test_Merge_FlagsChangedSourceForReview(t *testing.T) {
+	existing := xliff.NewDocument("en", "de")
+	existing.AddTransUnit("hello %s", xliff.WithTarget("hallo %s"))
+	existing.Files[0].Body.TransUnits[0].Note = "main.go:10"
+
+	fresh := xliff.NewDocument("en", "de")
+	fresh.AddTransUnit("hello there %s")
+	fresh.Files[0].Body.TransUnits[0].Note = "main.go:10"
+
+	merged := Merge(existing, fresh)
+
+	tu := merged.Files[0].Body.TransUnits[0]
+	if tu.Target.PlainText() != "hallo %s" {
+		t.Error("Merge should carry over the prior Target for review, not discard it")
+	}
+	if tu.Target.State != xliff.StateNeedsReviewTranslation {
+		t.Errorf("Target.State = %q, want %q", tu.Target.State, xliff.StateNeedsReviewTranslation)
+	}
+	if len(tu.AltTrans) != 1 || tu.AltTrans[0].Origin != "previous-version" {
+		t.Fatalf("expected a previous-version AltTrans, got %+v", tu.AltTrans)
+	}
+	if tu.AltTrans[0].Source.PlainText() != "hello %s" {
+		t.Errorf("AltTrans.Source = %q, want %q", tu.AltTrans[0].Source.PlainText(), "hello %s")
+	}
+}
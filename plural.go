@@ -0,0 +1,111 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pluralCategories is a small, curated table of the CLDR plural
+// categories each BCP-47 language subtag's cardinal plural rule can
+// select, mirroring (a hand-picked subset of) CLDR's plurals.xml. A
+// language not listed here is assumed to only ever select "other".
+var pluralCategories = map[string][]string{
+	"ar": {"zero", "one", "two", "few", "many", "other"},
+	"cs": {"one", "few", "many", "other"},
+	"da": {"one", "other"},
+	"de": {"one", "other"},
+	"en": {"one", "other"},
+	"es": {"one", "other"},
+	"fr": {"one", "other"},
+	"it": {"one", "other"},
+	"ja": {"other"},
+	"ko": {"other"},
+	"lv": {"zero", "one", "other"},
+	"pl": {"one", "few", "many", "other"},
+	"pt": {"one", "other"},
+	"ru": {"one", "few", "many", "other"},
+	"sk": {"one", "few", "many", "other"},
+	"th": {"other"},
+	"tr": {"one", "other"},
+	"uk": {"one", "few", "many", "other"},
+	"vi": {"other"},
+	"zh": {"other"},
+}
+
+// allPluralSelectors are the CLDR plural categories recognized anywhere,
+// regardless of language.
+var allPluralSelectors = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+// requiredPluralCategories returns the CLDR plural categories a
+// trans-unit's Cases must cover for lang, a BCP-47 language tag such as
+// "en" or "pt-BR".
+func requiredPluralCategories(lang string) []string {
+	primary := lang
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		primary = lang[:i]
+	}
+	if cats, ok := pluralCategories[strings.ToLower(primary)]; ok {
+		return cats
+	}
+	return []string{"other"}
+}
+
+func validPluralSelector(selector string) bool {
+	return allPluralSelectors[selector]
+}
+
+// validatePluralCases checks a TransUnit's plural Cases against the
+// CLDR categories required for file's target language.
+func validatePluralCases(transUnit TransUnit, file File) []ValidationError {
+	var errs []ValidationError
+	have := map[string]bool{}
+
+	for _, c := range transUnit.Cases {
+		if !validPluralSelector(c.Selector) {
+			errs = append(errs, ValidationError{
+				Code: UnknownPluralSelector,
+				Message: fmt.Sprintf("Translation unit '%s' in file '%s' has unknown plural selector '%s'",
+					transUnit.ID, file.Original, c.Selector),
+			})
+			continue
+		}
+		have[c.Selector] = true
+	}
+
+	for _, category := range requiredPluralCategories(file.TargetLanguage) {
+		if !have[category] {
+			errs = append(errs, ValidationError{
+				Code: MissingPluralCategory,
+				Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing required plural category '%s' for target language '%s'",
+					transUnit.ID, file.Original, category, file.TargetLanguage),
+			})
+		}
+	}
+
+	return errs
+}
+
+// WithPlural constructs a TransUnit's plural Cases from a map of CLDR
+// plural category (e.g. "one", "other") to that category's source text.
+// The resulting unit marshals as a <group restype="x-gettext-plurals">
+// rather than a plain <trans-unit>; Targets are left empty for
+// translators to fill in.
+func WithPlural(cases map[string]string) func(*TransUnit) {
+	return func(t *TransUnit) {
+		selectors := make([]string, 0, len(cases))
+		for selector := range cases {
+			selectors = append(selectors, selector)
+		}
+		sort.Strings(selectors)
+		for _, selector := range selectors {
+			t.Cases = append(t.Cases, Case{Selector: selector, Source: cases[selector]})
+		}
+	}
+}
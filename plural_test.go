@@ -0,0 +1,136 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+func newPluralUnit(t *testing.T, lang string, cases map[string]string) xliff.Document {
+	t.Helper()
+	doc := xliff.NewDocument("en", lang)
+	doc.Files[0].Original = "test.strings"
+	if err := doc.AddTransUnit("", xliff.WithPlural(cases)); err != nil {
+		t.Fatalf("AddTransUnit: %v", err)
+	}
+	return *doc
+}
+
+func Test_Plural_MarshalAsGroup(t *testing.T) {
+	doc := newPluralUnit(t, "en", map[string]string{"one": "%d item", "other": "%d items"})
+
+	data, err := xml.Marshal(doc.Files[0].Body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `restype="x-gettext-plurals"`) {
+		t.Errorf("expected a x-gettext-plurals group, got %s", data)
+	}
+	if !strings.Contains(string(data), `xl:case="one"`) || !strings.Contains(string(data), `xl:case="other"`) {
+		t.Errorf("expected xl:case extension attributes, got %s", data)
+	}
+}
+
+func Test_Plural_RoundTrip(t *testing.T) {
+	doc := newPluralUnit(t, "en", map[string]string{"one": "%d item", "other": "%d items"})
+
+	data, err := xml.Marshal(doc.Files[0].Body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var body xliff.Body
+	if err := xml.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(body.TransUnits) != 1 {
+		t.Fatalf("expected 1 trans-unit, got %d", len(body.TransUnits))
+	}
+	tu := body.TransUnits[0]
+	if len(tu.Cases) != 2 {
+		t.Fatalf("expected 2 cases, got %d", len(tu.Cases))
+	}
+	bySelector := map[string]xliff.Case{}
+	for _, c := range tu.Cases {
+		bySelector[c.Selector] = c
+	}
+	if bySelector["one"].Source != "%d item" || bySelector["other"].Source != "%d items" {
+		t.Errorf("unexpected round-tripped cases: %+v", tu.Cases)
+	}
+}
+
+func Test_Plural_RoundTripsWorkflowFields(t *testing.T) {
+	doc := newPluralUnit(t, "en", map[string]string{"one": "%d item", "other": "%d items"})
+	tu := &doc.Files[0].Body.TransUnits[0]
+	tu.Approved = xliff.Yes
+	tu.Translate = xliff.No
+	tu.AltTrans = []xliff.AltTrans{{
+		Origin: "previous-version",
+		Source: xliff.TextContent("%d thing"),
+		Target: xliff.TextContent("%d things"),
+	}}
+
+	data, err := xml.Marshal(doc.Files[0].Body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var body xliff.Body
+	if err := xml.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(body.TransUnits) != 1 {
+		t.Fatalf("expected 1 trans-unit, got %d", len(body.TransUnits))
+	}
+	got := body.TransUnits[0]
+	if got.Approved != xliff.Yes {
+		t.Errorf("Approved = %q, want %q", got.Approved, xliff.Yes)
+	}
+	if got.Translate != xliff.No {
+		t.Errorf("Translate = %q, want %q", got.Translate, xliff.No)
+	}
+	if len(got.AltTrans) != 1 || got.AltTrans[0].Origin != "previous-version" {
+		t.Fatalf("unexpected round-tripped AltTrans: %+v", got.AltTrans)
+	}
+	if got.AltTrans[0].Source.PlainText() != "%d thing" || got.AltTrans[0].Target.PlainText() != "%d things" {
+		t.Errorf("unexpected round-tripped AltTrans content: %+v", got.AltTrans[0])
+	}
+}
+
+func Test_IsComplete_RequiresEveryPluralCategory(t *testing.T) {
+	doc := newPluralUnit(t, "pl", map[string]string{"one": "%d rzecz", "few": "%d rzeczy", "many": "%d rzeczy", "other": "%d rzeczy"})
+	doc.Files[0].Body.TransUnits[0].Cases[0].Target = "%d rzecz"
+	// WithPlural sorts cases by selector, so Cases[0] is "few"; leave
+	// "many", "one" and "other" untranslated.
+
+	if doc.IsComplete() {
+		t.Error("expected IsComplete() to be false when a required plural category has no Target")
+	}
+}
+
+func Test_Validate_PluralErrors(t *testing.T) {
+	doc := newPluralUnit(t, "pl", map[string]string{"one": "%d rzecz", "bogus": "???"})
+
+	errs := doc.Validate()
+	var gotMissing, gotUnknown bool
+	for _, e := range errs {
+		switch e.Code {
+		case xliff.MissingPluralCategory:
+			gotMissing = true
+		case xliff.UnknownPluralSelector:
+			gotUnknown = true
+		}
+	}
+	if !gotMissing {
+		t.Error("expected Validate() to report MissingPluralCategory")
+	}
+	if !gotUnknown {
+		t.Error("expected Validate() to report UnknownPluralSelector")
+	}
+}
@@ -0,0 +1,376 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileHeader carries the attributes and <header> of the <file> element
+// a streamed TransUnit belongs to.
+type FileHeader struct {
+	Original       string
+	SourceLanguage string
+	Datatype       string
+	TargetLanguage string
+	Header         Header
+}
+
+// Decoder reads a large XLIFF document one TransUnit at a time, instead
+// of materialising the whole document in memory the way FromFile does.
+type Decoder struct {
+	d       *xml.Decoder
+	version string
+	files   []FileHeader
+}
+
+// NewDecoder returns a Decoder that reads an XLIFF document from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: xml.NewDecoder(r)}
+}
+
+// Version returns the document's version attribute, once Next has read
+// past the opening <xliff> element.
+func (dec *Decoder) Version() string {
+	return dec.version
+}
+
+// Files returns the FileHeader of every <file> element Next has read
+// past so far, in document order. Unlike Next's own return value, this
+// includes a <file> with no trans-units, which Next itself never
+// surfaces: FromFile uses it to reconcile empty files without
+// duplicating Next's token-walking loop.
+func (dec *Decoder) Files() []FileHeader {
+	return dec.files
+}
+
+func (dec *Decoder) currentHeader() FileHeader {
+	if len(dec.files) == 0 {
+		return FileHeader{}
+	}
+	return dec.files[len(dec.files)-1]
+}
+
+// Next returns the next TransUnit in the document and the FileHeader of
+// the <file> it belongs to. It returns io.EOF once the document is
+// exhausted. A <file> that contains no trans-units is never observed
+// here: callers that need every file, even empty ones, should use
+// FromFile, or read Files after Next returns io.EOF.
+func (dec *Decoder) Next() (TransUnit, FileHeader, error) {
+	for {
+		tok, err := dec.d.Token()
+		if err != nil {
+			return TransUnit{}, dec.currentHeader(), err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "xliff":
+			dec.version = attrValue(start, "version")
+		case "file":
+			dec.files = append(dec.files, FileHeader{
+				Original:       attrValue(start, "original"),
+				SourceLanguage: attrValue(start, "source-language"),
+				Datatype:       attrValue(start, "datatype"),
+				TargetLanguage: attrValue(start, "target-language"),
+			})
+		case "header":
+			if err := dec.d.DecodeElement(&dec.files[len(dec.files)-1].Header, &start); err != nil {
+				return TransUnit{}, dec.currentHeader(), err
+			}
+		case "trans-unit":
+			var tu TransUnit
+			if err := dec.d.DecodeElement(&tu, &start); err != nil {
+				return TransUnit{}, dec.currentHeader(), err
+			}
+			return tu, dec.currentHeader(), nil
+		case "group":
+			if attrValue(start, "restype") != "x-gettext-plurals" {
+				if err := dec.d.Skip(); err != nil {
+					return TransUnit{}, dec.currentHeader(), err
+				}
+				continue
+			}
+			tu, err := unmarshalPluralGroup(dec.d, start)
+			if err != nil {
+				return TransUnit{}, dec.currentHeader(), err
+			}
+			return tu, dec.currentHeader(), nil
+		}
+	}
+}
+
+// Encoder writes a large XLIFF document incrementally: one call to
+// WriteFile per <file>, followed by one call to WriteTransUnit per
+// trans-unit, followed by Close.
+type Encoder struct {
+	e        *xml.Encoder
+	w        io.Writer
+	started  bool
+	fileOpen bool
+}
+
+// NewEncoder returns an Encoder that writes an XLIFF document to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{e: xml.NewEncoder(w), w: w}
+}
+
+// WriteFile closes any previously open <file> and opens a new one with
+// the given header. It must be called at least once before the first
+// WriteTransUnit.
+func (enc *Encoder) WriteFile(header FileHeader) error {
+	if err := enc.closeFile(); err != nil {
+		return err
+	}
+
+	if !enc.started {
+		if _, err := io.WriteString(enc.w, xml.Header); err != nil {
+			return err
+		}
+		if err := enc.e.EncodeToken(xliffStart); err != nil {
+			return err
+		}
+		enc.started = true
+	}
+
+	start := xml.StartElement{
+		Name: xml.Name{Local: "file"},
+		Attr: attrs(
+			"original", header.Original,
+			"source-language", header.SourceLanguage,
+			"datatype", header.Datatype,
+			"target-language", header.TargetLanguage,
+		),
+	}
+	if err := enc.e.EncodeToken(start); err != nil {
+		return err
+	}
+	if header.Header.Tool != (Tool{}) {
+		if err := enc.e.EncodeElement(header.Header, xml.StartElement{Name: xml.Name{Local: "header"}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "body"}}); err != nil {
+		return err
+	}
+
+	enc.fileOpen = true
+	return nil
+}
+
+// WriteTransUnit writes tu to the currently open file.
+func (enc *Encoder) WriteTransUnit(tu TransUnit) error {
+	if !enc.fileOpen {
+		return errors.New("xliff: WriteTransUnit called before WriteFile")
+	}
+	if len(tu.Cases) == 0 {
+		return enc.e.EncodeElement(tu, xml.StartElement{Name: xml.Name{Local: "trans-unit"}})
+	}
+	return marshalPluralGroup(enc.e, tu)
+}
+
+// Close closes the currently open file, if any, ends the document, and
+// flushes the underlying xml.Encoder.
+func (enc *Encoder) Close() error {
+	if err := enc.closeFile(); err != nil {
+		return err
+	}
+	if enc.started {
+		if err := enc.e.EncodeToken(xliffStart.End()); err != nil {
+			return err
+		}
+	}
+	return enc.e.Flush()
+}
+
+func (enc *Encoder) closeFile() error {
+	if !enc.fileOpen {
+		return nil
+	}
+	if err := enc.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "body"}}); err != nil {
+		return err
+	}
+	if err := enc.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "file"}}); err != nil {
+		return err
+	}
+	enc.fileOpen = false
+	return nil
+}
+
+var xliffStart = xml.StartElement{
+	Name: xml.Name{Local: "xliff"},
+	Attr: attrs(
+		"version", "1.2",
+		"xmlns", "urn:oasis:names:tc:xliff:document:1.2",
+		"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+		"xsi:schemaLocation", "urn:oasis:names:tc:xliff:document:1.2 http://docs.oasis-open.org/xliff/v1.2/os/xliff-core-1.2-strict.xsd",
+	),
+}
+
+// ValidateStream applies Document.Validate's per-trans-unit checks to an
+// XLIFF document without materialising the whole document in memory,
+// and reports a trans-unit whose file's source or target language
+// differs from the first file's. It does not check the document's
+// version or the <file> attributes themselves, and a <file> with no
+// trans-units is not examined. Every
+// reported ValidationError's Message is prefixed with the byte offset
+// (from the underlying xml.Decoder) at which the problem was found.
+func ValidateStream(r io.Reader) []ValidationError {
+	dec := NewDecoder(r)
+
+	var errs []ValidationError
+	var sourceLang, targetLang string
+	seenFile := false
+
+	for {
+		offset := dec.d.InputOffset()
+		tu, header, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, ValidationError{
+				Code:    StreamDecodeError,
+				Message: fmt.Sprintf("byte offset %d: %v", offset, err),
+			})
+			break
+		}
+
+		if !seenFile {
+			sourceLang, targetLang = header.SourceLanguage, header.TargetLanguage
+			seenFile = true
+		} else if header.SourceLanguage != sourceLang || header.TargetLanguage != targetLang {
+			code := InconsistentSourceLanguage
+			if header.SourceLanguage == sourceLang {
+				code = InconsistentTargetLanguage
+			}
+			errs = append(errs, ValidationError{
+				Code: code,
+				Message: fmt.Sprintf("byte offset %d: File '%s' has inconsistent source/target language",
+					offset, header.Original),
+			})
+		}
+
+		file := File{Original: header.Original, SourceLanguage: header.SourceLanguage, TargetLanguage: header.TargetLanguage}
+		before := len(errs)
+
+		if tu.ID == "" {
+			errs = append(errs, ValidationError{
+				Code:    MissingTransUnitID,
+				Message: fmt.Sprintf("Translation unit in file '%s' is missing 'id' attribute", header.Original),
+			})
+		}
+		if len(tu.Cases) > 0 {
+			errs = append(errs, validatePluralCases(tu, file)...)
+		} else {
+			if tu.Source.IsEmpty() {
+				errs = append(errs, ValidationError{
+					Code:    MissingTransUnitSource,
+					Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'source' attribute", tu.ID, header.Original),
+				})
+			}
+			if tu.Target.IsEmpty() {
+				errs = append(errs, ValidationError{
+					Code:    MissingTransUnitTarget,
+					Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'target' attribute", tu.ID, header.Original),
+				})
+			}
+			errs = append(errs, validateInlineContent(tu, file, "source", tu.Source)...)
+			errs = append(errs, validateInlineContent(tu, file, "target", tu.Target.Content)...)
+		}
+		errs = append(errs, validateWorkflow(tu, file)...)
+
+		for i := before; i < len(errs); i++ {
+			errs[i].Message = fmt.Sprintf("byte offset %d: %s", offset, errs[i].Message)
+		}
+	}
+
+	return errs
+}
+
+// FromFile reads an XLIFF Document from disk. It is a thin wrapper
+// around Decoder: Next supplies the trans-units in order, and Files
+// supplies the FileHeader of every <file> Next has read past, including
+// one with no trans-units, so an empty <file> still ends up in the
+// returned Document rather than silently dropped.
+func FromFile(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return &Document{}, err
+	}
+	defer f.Close()
+
+	dec := NewDecoder(f)
+	document := &Document{}
+	synced := 0
+
+	sync := func() {
+		for _, h := range dec.Files()[synced:] {
+			document.Files = append(document.Files, File{
+				Original:       h.Original,
+				SourceLanguage: h.SourceLanguage,
+				Datatype:       h.Datatype,
+				TargetLanguage: h.TargetLanguage,
+				Header:         h.Header,
+			})
+		}
+		synced = len(dec.Files())
+	}
+
+	for {
+		tu, _, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &Document{}, err
+		}
+		sync()
+		curFile := &document.Files[len(document.Files)-1]
+		curFile.Body.TransUnits = append(curFile.Body.TransUnits, tu)
+	}
+	sync()
+	document.Version = dec.Version()
+
+	return document, nil
+}
+
+// ToFile writes an XLIFF Document to disk, streaming it through an
+// Encoder rather than marshalling the whole document at once.
+func (d *Document) ToFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := NewEncoder(f)
+	for _, file := range d.Files {
+		if err := enc.WriteFile(FileHeader{
+			Original:       file.Original,
+			SourceLanguage: file.SourceLanguage,
+			Datatype:       file.Datatype,
+			TargetLanguage: file.TargetLanguage,
+			Header:         file.Header,
+		}); err != nil {
+			return err
+		}
+		for _, tu := range file.Body.TransUnits {
+			if err := enc.WriteTransUnit(tu); err != nil {
+				return err
+			}
+		}
+	}
+
+	return enc.Close()
+}
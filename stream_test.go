@@ -0,0 +1,179 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+const streamDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2">
+<file original="One.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+<trans-unit id="0"><source>Hallo Welt</source><target>Hello World</target></trans-unit>
+<trans-unit id="1"><source>Tschuss</source></trans-unit>
+</body>
+</file>
+</xliff>`
+
+func Test_Decoder_Next(t *testing.T) {
+	dec := xliff.NewDecoder(strings.NewReader(streamDoc))
+
+	tu, header, err := dec.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if header.Original != "One.strings" || header.SourceLanguage != "de" || header.TargetLanguage != "en" {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if tu.ID != "0" || tu.Source.PlainText() != "Hallo Welt" || tu.Target.PlainText() != "Hello World" {
+		t.Errorf("unexpected trans-unit: %+v", tu)
+	}
+
+	tu, _, err = dec.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if tu.ID != "1" || tu.Source.PlainText() != "Tschuss" {
+		t.Errorf("unexpected second trans-unit: %+v", tu)
+	}
+
+	if _, _, err := dec.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func Test_Decoder_FilesIncludesEmptyFile(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2">
+<file original="One.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+<trans-unit id="0"><source>Hallo Welt</source></trans-unit>
+</body>
+</file>
+<file original="Empty.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+</body>
+</file>
+</xliff>`
+
+	dec := xliff.NewDecoder(strings.NewReader(doc))
+	for {
+		if _, _, err := dec.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+
+	files := dec.Files()
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[1].Original != "Empty.strings" {
+		t.Errorf("unexpected second file: %+v", files[1])
+	}
+}
+
+func Test_Encoder_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := xliff.NewEncoder(&buf)
+
+	if err := enc.WriteFile(xliff.FileHeader{
+		Original:       "One.strings",
+		SourceLanguage: "de",
+		Datatype:       "plaintext",
+		TargetLanguage: "en",
+	}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := enc.WriteTransUnit(xliff.TransUnit{ID: "0", Source: xliff.TextContent("Hallo Welt"), Target: xliff.Target{Content: xliff.TextContent("Hello World")}}); err != nil {
+		t.Fatalf("WriteTransUnit: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dec := xliff.NewDecoder(bytes.NewReader(buf.Bytes()))
+	tu, header, err := dec.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if header.Original != "One.strings" {
+		t.Errorf("header.Original = %q, want %q", header.Original, "One.strings")
+	}
+	if tu.Source.PlainText() != "Hallo Welt" || tu.Target.PlainText() != "Hello World" {
+		t.Errorf("unexpected trans-unit: %+v", tu)
+	}
+}
+
+func Test_FromFile_PreservesEmptyFile(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2">
+<file original="One.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+<trans-unit id="0"><source>Hallo Welt</source><target>Hello World</target></trans-unit>
+</body>
+</file>
+<file original="Empty.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+</body>
+</file>
+</xliff>`
+
+	path := filepath.Join(t.TempDir(), "doc.xliff")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	document, err := xliff.FromFile(path)
+	if err != nil {
+		t.Fatalf("FromFile: %v", err)
+	}
+	if len(document.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(document.Files))
+	}
+	if document.Files[1].Original != "Empty.strings" || document.Files[1].TargetLanguage != "en" {
+		t.Errorf("unexpected second file: %+v", document.Files[1])
+	}
+	if len(document.Files[1].Body.TransUnits) != 0 {
+		t.Errorf("expected the empty file to have 0 trans-units, got %d", len(document.Files[1].Body.TransUnits))
+	}
+}
+
+func Test_ValidateStream(t *testing.T) {
+	bad := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2">
+<file original="One.strings" source-language="de" target-language="en" datatype="plaintext">
+<body>
+<trans-unit id="0"><source>Hallo Welt</source></trans-unit>
+</body>
+</file>
+</xliff>`
+
+	errs := xliff.ValidateStream(strings.NewReader(bad))
+	if len(errs) == 0 {
+		t.Fatal("expected at least one validation error")
+	}
+	found := false
+	for _, e := range errs {
+		if e.Code == xliff.MissingTransUnitTarget {
+			found = true
+			if !strings.Contains(e.Error(), "byte offset") {
+				t.Errorf("expected error message to carry a byte offset, got %q", e.Error())
+			}
+		}
+	}
+	if !found {
+		t.Error("expected ValidateStream to report MissingTransUnitTarget")
+	}
+}
@@ -0,0 +1,279 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+// Package v2 reads and writes the OASIS XLIFF 2.0 core namespace
+// (urn:oasis:names:tc:xliff:document:2.0) as a peer format to the
+// existing XLIFF 1.2 support in the parent xliff package.
+package v2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"iter"
+
+	"github.com/truesch/xliff"
+)
+
+// Namespace is the XLIFF 2.0 core namespace.
+const Namespace = "urn:oasis:names:tc:xliff:document:2.0"
+
+// State is the value of a <segment>'s state attribute.
+type State string
+
+const (
+	StateInitial  State = "initial"
+	StateReviewed State = "reviewed"
+	StateFinal    State = "final"
+)
+
+type Document struct {
+	Version string `xml:"version,attr"`
+	SrcLang string `xml:"srcLang,attr"`
+	TrgLang string `xml:"trgLang,attr"`
+	Files   []File `xml:"file"`
+}
+
+type documentExport struct {
+	*Document
+	XMLName xml.Name `xml:"xliff"`
+	Xmlns   string   `xml:"xmlns,attr"`
+}
+
+type File struct {
+	ID    string `xml:"id,attr"`
+	Units []Unit `xml:"unit"`
+}
+
+// Unit is an XLIFF 2.0 <unit>: one or more translatable segments sharing
+// an id and notes.
+type Unit struct {
+	ID       string    `xml:"id,attr"`
+	Notes    []string  `xml:"notes>note"`
+	Segments []Segment `xml:"segment"`
+}
+
+// Segment is an XLIFF 2.0 <segment>: a single source/target pair with
+// its own workflow state.
+type Segment struct {
+	ID       string        `xml:"id,attr,omitempty"`
+	State    State         `xml:"state,attr,omitempty"`
+	SubState string        `xml:"subState,attr,omitempty"`
+	Source   xliff.Content `xml:"source"`
+	Target   xliff.Content `xml:"target"`
+
+	unitID string
+}
+
+// UnitID identifies s as "<unit id>/<segment id>", or just the unit id
+// when the segment itself has none (the common case of one segment per
+// unit). The unit id is filled in by Document.Units; on a Segment not
+// obtained from Units it is empty.
+func (s *Segment) UnitID() string {
+	if s.ID == "" {
+		return s.unitID
+	}
+	return s.unitID + "/" + s.ID
+}
+
+func (s *Segment) SourceContent() xliff.Content { return s.Source }
+func (s *Segment) TargetContent() xliff.Content { return s.Target }
+
+// FromFile reads an XLIFF 2.0 Document from disk.
+func FromFile(path string) (*Document, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &Document{}, err
+	}
+
+	var document Document
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return &Document{}, err
+	}
+
+	return &document, nil
+}
+
+// ToFile writes an XLIFF 2.0 Document to disk.
+func (d *Document) ToFile(path string) error {
+	export := &documentExport{
+		Document: d,
+		Xmlns:    Namespace,
+	}
+
+	data, err := xml.Marshal(export)
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+
+	return ioutil.WriteFile(path, data, 0664)
+}
+
+// Units iterates every segment across all of d's files.
+func (d *Document) Units() iter.Seq[xliff.Unit] {
+	return func(yield func(xliff.Unit) bool) {
+		for fi := range d.Files {
+			for ui := range d.Files[fi].Units {
+				unit := &d.Files[fi].Units[ui]
+				for si := range unit.Segments {
+					seg := &unit.Segments[si]
+					seg.unitID = unit.ID
+					if !yield(seg) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func (d *Document) SourceLang() string { return d.SrcLang }
+func (d *Document) TargetLang() string { return d.TrgLang }
+
+var _ xliff.Catalog = (*Document)(nil)
+var _ xliff.Unit = (*Segment)(nil)
+
+// Open reads the XLIFF file at path and returns it as a xliff.Catalog,
+// sniffing the root element's version attribute to decide whether to
+// decode it as XLIFF 1.2 or 2.0.
+//
+// This dispatcher lives here, rather than on the 1.2-only FromFile in
+// the parent xliff package, because it needs to see both *Document
+// types: putting it in the parent package would make it import this
+// one, which already imports the parent for Content and Catalog.
+func Open(path string) (xliff.Catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sniffVersion(data) {
+	case "2.0":
+		var document Document
+		if err := xml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		return &document, nil
+	case "1.2", "":
+		return xliff.FromFile(path)
+	default:
+		return nil, fmt.Errorf("v2: unsupported xliff version %q", sniffVersion(data))
+	}
+}
+
+// Save writes c to path in its own native format.
+func Save(c xliff.Catalog, path string) error {
+	switch doc := c.(type) {
+	case *Document:
+		return doc.ToFile(path)
+	case *xliff.Document:
+		return doc.ToFile(path)
+	default:
+		return fmt.Errorf("v2: unsupported Catalog implementation %T", c)
+	}
+}
+
+func sniffVersion(data []byte) string {
+	var probe struct {
+		Version string `xml:"version,attr"`
+	}
+	_ = xml.Unmarshal(data, &probe)
+	return probe.Version
+}
+
+// ConvertToV2 converts a v1.2 Document into a v2.0 Document. Each
+// TransUnit becomes a <unit>, with the TransUnit's ID reused as the
+// unit id and its Note preserved as the unit's sole note. A plain
+// TransUnit becomes a unit with a single <segment>; a TransUnit with
+// plural Cases instead becomes one <segment> per case, with the
+// segment's id set to the CLDR plural category (e.g. "one", "other")
+// so the cases survive the conversion. The conversion is otherwise
+// lossy: v1.2 workflow attributes that have no v2.0 core equivalent
+// (e.g. a <trans-unit>'s "approved" attribute) are dropped.
+func ConvertToV2(d *xliff.Document) (*Document, error) {
+	out := &Document{Version: "2.0"}
+	if len(d.Files) > 0 {
+		out.SrcLang = d.Files[0].SourceLanguage
+		out.TrgLang = d.Files[0].TargetLanguage
+	}
+
+	for _, file := range d.Files {
+		v2File := File{ID: file.Original}
+		for _, tu := range file.Body.TransUnits {
+			unit := Unit{ID: tu.ID}
+			if tu.Note != "" {
+				unit.Notes = []string{tu.Note}
+			}
+			if len(tu.Cases) > 0 {
+				for _, c := range tu.Cases {
+					unit.Segments = append(unit.Segments, Segment{
+						ID:     c.Selector,
+						Source: xliff.TextContent(c.Source),
+						Target: xliff.TextContent(c.Target),
+					})
+				}
+			} else {
+				unit.Segments = []Segment{{
+					Source: tu.Source,
+					Target: tu.Target.Content,
+				}}
+			}
+			v2File.Units = append(v2File.Units, unit)
+		}
+		out.Files = append(out.Files, v2File)
+	}
+
+	return out, nil
+}
+
+// ConvertFromV2 converts a v2.0 Document into a v1.2 Document. Every
+// segment contributes one <trans-unit>, named "<unit id>/<segment id>",
+// or just "<unit id>" when the segment has no id; a unit's notes are
+// joined with "\n" into the resulting <trans-unit>'s Note.
+func ConvertFromV2(d *Document) (*xliff.Document, error) {
+	out := &xliff.Document{Version: "1.2"}
+
+	for _, file := range d.Files {
+		v1File := xliff.File{
+			Original:       file.ID,
+			Datatype:       "plaintext",
+			SourceLanguage: d.SrcLang,
+			TargetLanguage: d.TrgLang,
+		}
+		for _, unit := range file.Units {
+			note := joinNotes(unit.Notes)
+			for _, seg := range unit.Segments {
+				id := unit.ID
+				if seg.ID != "" {
+					id = unit.ID + "/" + seg.ID
+				}
+				v1File.Body.TransUnits = append(v1File.Body.TransUnits, xliff.TransUnit{
+					ID:     id,
+					Source: seg.Source,
+					Target: xliff.Target{Content: seg.Target},
+					Note:   note,
+				})
+			}
+		}
+		out.Files = append(out.Files, v1File)
+	}
+
+	return out, nil
+}
+
+func joinNotes(notes []string) string {
+	switch len(notes) {
+	case 0:
+		return ""
+	case 1:
+		return notes[0]
+	default:
+		out := notes[0]
+		for _, n := range notes[1:] {
+			out += "\n" + n
+		}
+		return out
+	}
+}
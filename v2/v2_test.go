@@ -0,0 +1,94 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package v2_test
+
+import (
+	"testing"
+
+	"github.com/truesch/xliff"
+	"github.com/truesch/xliff/v2"
+)
+
+func Test_ConvertToV2AndBack(t *testing.T) {
+	doc := xliff.NewDocument("de", "en")
+	doc.Files[0].Original = "One.strings"
+	if err := doc.AddTransUnit("Hallo Welt", xliff.WithTarget("Hello World"), xliff.WithNote("greeting")); err != nil {
+		t.Fatalf("AddTransUnit: %v", err)
+	}
+
+	v2doc, err := v2.ConvertToV2(doc)
+	if err != nil {
+		t.Fatalf("ConvertToV2: %v", err)
+	}
+	if v2doc.Version != "2.0" {
+		t.Errorf("Version = %q, want %q", v2doc.Version, "2.0")
+	}
+	if len(v2doc.Files) != 1 || len(v2doc.Files[0].Units) != 1 {
+		t.Fatalf("unexpected v2 document shape: %+v", v2doc)
+	}
+	unit := v2doc.Files[0].Units[0]
+	if len(unit.Notes) != 1 || unit.Notes[0] != "greeting" {
+		t.Errorf("unit notes = %v, want [greeting]", unit.Notes)
+	}
+
+	back, err := v2.ConvertFromV2(v2doc)
+	if err != nil {
+		t.Fatalf("ConvertFromV2: %v", err)
+	}
+	if len(back.Files) != 1 || len(back.Files[0].Body.TransUnits) != 1 {
+		t.Fatalf("unexpected round-tripped document shape: %+v", back)
+	}
+	tu := back.Files[0].Body.TransUnits[0]
+	if tu.Source.PlainText() != "Hallo Welt" || tu.Target.PlainText() != "Hello World" {
+		t.Errorf("unexpected round-tripped trans-unit: %+v", tu)
+	}
+	if tu.Note != "greeting" {
+		t.Errorf("Note = %q, want %q", tu.Note, "greeting")
+	}
+}
+
+func Test_ConvertToV2_FlattensPluralCases(t *testing.T) {
+	doc := xliff.NewDocument("en", "pl")
+	doc.Files[0].Original = "One.strings"
+	if err := doc.AddTransUnit("", xliff.WithPlural(map[string]string{"one": "%d rzecz", "other": "%d rzeczy"})); err != nil {
+		t.Fatalf("AddTransUnit: %v", err)
+	}
+
+	v2doc, err := v2.ConvertToV2(doc)
+	if err != nil {
+		t.Fatalf("ConvertToV2: %v", err)
+	}
+	if len(v2doc.Files) != 1 || len(v2doc.Files[0].Units) != 1 {
+		t.Fatalf("unexpected v2 document shape: %+v", v2doc)
+	}
+	segments := v2doc.Files[0].Units[0].Segments
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments (one per plural case), got %d", len(segments))
+	}
+	bySelector := map[string]string{}
+	for _, seg := range segments {
+		bySelector[seg.ID] = seg.Source.PlainText()
+	}
+	if bySelector["one"] != "%d rzecz" || bySelector["other"] != "%d rzeczy" {
+		t.Errorf("unexpected segment sources: %+v", bySelector)
+	}
+}
+
+func Test_Catalog_Units(t *testing.T) {
+	doc := xliff.NewDocument("de", "en")
+	doc.AddTransUnit("Hallo Welt", xliff.WithTarget("Hello World"))
+
+	var cat xliff.Catalog = doc
+	count := 0
+	for u := range cat.Units() {
+		count++
+		if u.SourceContent().PlainText() != "Hallo Welt" {
+			t.Errorf("unexpected unit source: %q", u.SourceContent().PlainText())
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected 1 unit, got %d", count)
+	}
+}
@@ -0,0 +1,173 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// YesNoAttr is the "yes"/"no" value of an XLIFF boolean attribute, used
+// by TransUnit's "approved" and "translate" attributes. The zero value
+// means the attribute was absent from the document.
+type YesNoAttr string
+
+const (
+	Yes YesNoAttr = "yes"
+	No  YesNoAttr = "no"
+)
+
+// Bool reports a's boolean value, treating an absent attribute (the
+// zero value) as def.
+func (a YesNoAttr) Bool(def bool) bool {
+	switch a {
+	case Yes:
+		return true
+	case No:
+		return false
+	default:
+		return def
+	}
+}
+
+// State is the value of a <target>'s state attribute.
+type State string
+
+const (
+	StateNew                    State = "new"
+	StateNeedsTranslation       State = "needs-translation"
+	StateNeedsReviewTranslation State = "needs-review-translation"
+	StateTranslated             State = "translated"
+	StateSignedOff              State = "signed-off"
+	StateFinal                  State = "final"
+)
+
+// stateRank orders State by how far along the translation workflow it
+// represents, so IsCompleteAtLeast can compare states without knowing
+// every intermediate value. An unrecognized or absent state ranks
+// lowest, i.e. it never satisfies a minimum of StateNew or higher.
+var stateRank = map[State]int{
+	StateNew:                    1,
+	StateNeedsTranslation:       2,
+	StateNeedsReviewTranslation: 3,
+	StateTranslated:             4,
+	StateSignedOff:              5,
+	StateFinal:                  6,
+}
+
+func isKnownState(s State) bool {
+	_, ok := stateRank[s]
+	return ok
+}
+
+// Target is a TransUnit's <target>: translated Content plus the
+// workflow attributes CAT tools attach to it.
+type Target struct {
+	Content
+	State          State
+	StateQualifier string
+}
+
+// MarshalXML implements xml.Marshaler, adding the state and
+// state-qualifier attributes around Content's own marshalling.
+func (t Target) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.State != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "state"}, Value: string(t.State)})
+	}
+	if t.StateQualifier != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "state-qualifier"}, Value: t.StateQualifier})
+	}
+	return t.Content.MarshalXML(e, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (t *Target) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	t.State = State(attrValue(start, "state"))
+	t.StateQualifier = attrValue(start, "state-qualifier")
+	return t.Content.UnmarshalXML(d, start)
+}
+
+// AltTrans is a <alt-trans>: a translation-memory suggestion attached to
+// a TransUnit by upstream tooling, not itself part of the unit's own
+// translation.
+type AltTrans struct {
+	MatchQuality string  `xml:"match-quality,attr,omitempty"`
+	Origin       string  `xml:"origin,attr,omitempty"`
+	Source       Content `xml:"source"`
+	Target       Content `xml:"target"`
+}
+
+// IsApproved reports whether t has been approved; XLIFF defaults
+// "approved" to "no" when the attribute is absent.
+func (t TransUnit) IsApproved() bool { return t.Approved.Bool(false) }
+
+// ShouldTranslate reports whether t should be translated; XLIFF
+// defaults "translate" to "yes" when the attribute is absent.
+func (t TransUnit) ShouldTranslate() bool { return t.Translate.Bool(true) }
+
+// IsCompleteAtLeast reports true if every trans-unit's Target has
+// reached at least minState in the translation workflow. A trans-unit
+// with plural Cases has no single Target state, so it is instead
+// required to have a non-empty Target for every CLDR category
+// IsComplete would require.
+func (d *Document) IsCompleteAtLeast(minState State) bool {
+	want := stateRank[minState]
+	for _, file := range d.Files {
+		required := requiredPluralCategories(file.TargetLanguage)
+		for _, transUnit := range file.Body.TransUnits {
+			if len(transUnit.Cases) > 0 {
+				have := map[string]bool{}
+				for _, c := range transUnit.Cases {
+					if c.Target != "" {
+						have[c.Selector] = true
+					}
+				}
+				for _, category := range required {
+					if !have[category] {
+						return false
+					}
+				}
+				continue
+			}
+			if transUnit.Source.IsEmpty() {
+				return false
+			}
+			if stateRank[transUnit.Target.State] < want {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateWorkflow checks transUnit's Target.State and its combination
+// with Approved for problems Validate should report.
+func validateWorkflow(transUnit TransUnit, file File) []ValidationError {
+	var errs []ValidationError
+
+	state := transUnit.Target.State
+	if state != "" && !isKnownState(state) {
+		errs = append(errs, ValidationError{
+			Code: UnknownTargetState,
+			Message: fmt.Sprintf("Translation unit '%s' in file '%s' has unknown target state '%s'",
+				transUnit.ID, file.Original, state),
+		})
+	}
+
+	// A unit cannot be approved before it has actually been
+	// translated: there is nothing yet for a reviewer to have signed
+	// off on. stateRank ranks an unknown or absent state below every
+	// real state (including StateTranslated), so this also catches an
+	// approved unit with a bogus or missing target state.
+	if transUnit.IsApproved() && stateRank[state] < stateRank[StateTranslated] {
+		errs = append(errs, ValidationError{
+			Code: IllegalStateTransition,
+			Message: fmt.Sprintf("Translation unit '%s' in file '%s' is approved but its target state is '%s'",
+				transUnit.ID, file.Original, state),
+		})
+	}
+
+	return errs
+}
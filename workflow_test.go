@@ -0,0 +1,153 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/
+
+package xliff_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/truesch/xliff"
+)
+
+// marshalTarget marshals target as a <target> element. xml.Marshal
+// won't do: it only honors an XMLName struct tag when deriving the
+// start element itself, and Target implements xml.Marshaler only via
+// its own MarshalXML, so a wrapper struct's XMLName is never
+// consulted. Calling MarshalXML directly with an explicit start
+// element sidesteps that.
+func marshalTarget(t *testing.T, target xliff.Target) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := target.MarshalXML(enc, xml.StartElement{Name: xml.Name{Local: "target"}}); err != nil {
+		t.Fatalf("MarshalXML: %v", err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	return buf.String()
+}
+
+func Test_YesNoAttr_Bool(t *testing.T) {
+	if !xliff.Yes.Bool(false) {
+		t.Error("Yes.Bool(false) = false, want true")
+	}
+	if xliff.No.Bool(true) {
+		t.Error("No.Bool(true) = true, want false")
+	}
+	if !xliff.YesNoAttr("").Bool(true) {
+		t.Error("an absent attribute should fall back to the provided default")
+	}
+}
+
+func Test_TransUnit_ApprovedTranslateDefaults(t *testing.T) {
+	var tu xliff.TransUnit
+	if tu.IsApproved() {
+		t.Error("IsApproved() should default to false when 'approved' is absent")
+	}
+	if !tu.ShouldTranslate() {
+		t.Error("ShouldTranslate() should default to true when 'translate' is absent")
+	}
+
+	tu.Approved = xliff.Yes
+	tu.Translate = xliff.No
+	if !tu.IsApproved() {
+		t.Error("IsApproved() should be true when approved=\"yes\"")
+	}
+	if tu.ShouldTranslate() {
+		t.Error("ShouldTranslate() should be false when translate=\"no\"")
+	}
+}
+
+func Test_Target_RoundTripStateAndQualifier(t *testing.T) {
+	target := xliff.Target{
+		Content:        xliff.TextContent("Hallo Welt"),
+		State:          xliff.StateTranslated,
+		StateQualifier: "mt-suggestion",
+	}
+
+	data := marshalTarget(t, target)
+	if data != `<target state="translated" state-qualifier="mt-suggestion">Hallo Welt</target>` {
+		t.Fatalf("MarshalXML() = %q", data)
+	}
+
+	var got xliff.Target
+	if err := xml.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.PlainText() != "Hallo Welt" || got.State != xliff.StateTranslated || got.StateQualifier != "mt-suggestion" {
+		t.Errorf("unexpected round-tripped target: %+v", got)
+	}
+}
+
+func Test_TransUnit_AltTrans_RoundTrip(t *testing.T) {
+	doc := xliff.NewDocument("en", "de")
+	if err := doc.AddTransUnit("hello", xliff.WithTarget("hallo")); err != nil {
+		t.Fatalf("AddTransUnit: %v", err)
+	}
+	tu := &doc.Files[0].Body.TransUnits[0]
+	tu.AltTrans = append(tu.AltTrans, xliff.AltTrans{
+		Origin: "previous-version",
+		Source: xliff.TextContent("hi"),
+		Target: xliff.TextContent("hallöchen"),
+	})
+
+	data, err := xml.Marshal(doc.Files[0].Body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var body xliff.Body
+	if err := xml.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(body.TransUnits) != 1 || len(body.TransUnits[0].AltTrans) != 1 {
+		t.Fatalf("expected 1 trans-unit with 1 alt-trans, got %+v", body.TransUnits)
+	}
+	alt := body.TransUnits[0].AltTrans[0]
+	if alt.Origin != "previous-version" || alt.Source.PlainText() != "hi" || alt.Target.PlainText() != "hallöchen" {
+		t.Errorf("unexpected round-tripped alt-trans: %+v", alt)
+	}
+}
+
+func Test_IsCompleteAtLeast(t *testing.T) {
+	doc := xliff.NewDocument("en", "de")
+	doc.AddTransUnit("hello", xliff.WithTarget("hallo"))
+	doc.Files[0].Body.TransUnits[0].Target.State = xliff.StateTranslated
+
+	if !doc.IsCompleteAtLeast(xliff.StateTranslated) {
+		t.Error("expected IsCompleteAtLeast(StateTranslated) to be true")
+	}
+	if doc.IsCompleteAtLeast(xliff.StateSignedOff) {
+		t.Error("expected IsCompleteAtLeast(StateSignedOff) to be false")
+	}
+}
+
+func Test_Validate_WorkflowErrors(t *testing.T) {
+	doc := xliff.NewDocument("en", "de")
+	doc.AddTransUnit("hello", xliff.WithTarget("hallo"))
+
+	tu := &doc.Files[0].Body.TransUnits[0]
+	tu.Target.State = "bogus"
+	tu.Approved = xliff.Yes
+
+	errs := doc.Validate()
+	var gotUnknown, gotIllegal bool
+	for _, e := range errs {
+		switch e.Code {
+		case xliff.UnknownTargetState:
+			gotUnknown = true
+		case xliff.IllegalStateTransition:
+			gotIllegal = true
+		}
+	}
+	if !gotUnknown {
+		t.Error("expected Validate() to report UnknownTargetState")
+	}
+	if !gotIllegal {
+		t.Error("expected Validate() to report IllegalStateTransition for an approved unit in an unknown/untranslated state")
+	}
+}
@@ -8,8 +8,8 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"strconv"
+	"strings"
 )
 
 type DocumentExport struct {
@@ -46,63 +46,208 @@ type Tool struct {
 }
 
 type Body struct {
-	TransUnits []TransUnit `xml:"trans-unit"`
+	TransUnits []TransUnit
 }
 
-// Returns a new, empty xliff file.
-// datatype will always be "plaintext" and version will always be "1.2"
-func NewDocument(sl string, tl string) *Document {
-	file := File{
-		Datatype:       "plaintext",
-		SourceLanguage: sl,
-		TargetLanguage: tl,
-		Header:         Header{},
-		Body:           Body{},
+// pluralGroupNamespace is declared as xmlns:xl on every
+// x-gettext-plurals group so its child trans-units can carry the
+// plural-category extension attribute xl:case.
+const pluralGroupNamespace = "urn:x-gettext:plural"
+
+// MarshalXML implements xml.Marshaler. A TransUnit with Cases set
+// marshals as a <group restype="x-gettext-plurals"> containing one
+// <trans-unit> per case; every other TransUnit marshals as a plain
+// <trans-unit>, as before.
+func (b Body) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
 	}
-	return &Document{
-		Version: "1.2",
-		Files:   []File{file},
+	for _, tu := range b.TransUnits {
+		if len(tu.Cases) == 0 {
+			if err := e.EncodeElement(tu, xml.StartElement{Name: xml.Name{Local: "trans-unit"}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := marshalPluralGroup(e, tu); err != nil {
+			return err
+		}
 	}
+	return e.EncodeToken(start.End())
 }
 
-// Reads XLIFF Document from disk
-func FromFile(path string) (*Document, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return &Document{}, err
+func marshalPluralGroup(e *xml.Encoder, tu TransUnit) error {
+	group := xml.StartElement{
+		Name: xml.Name{Local: "group"},
+		Attr: attrs("restype", "x-gettext-plurals", "xmlns:xl", pluralGroupNamespace,
+			"approved", string(tu.Approved), "translate", string(tu.Translate)),
 	}
-
-	var document Document
-	if err := xml.Unmarshal(data, &document); err != nil {
-		return &Document{}, err
+	if err := e.EncodeToken(group); err != nil {
+		return err
+	}
+	for _, alt := range tu.AltTrans {
+		if err := e.EncodeElement(alt, xml.StartElement{Name: xml.Name{Local: "alt-trans"}}); err != nil {
+			return err
+		}
+	}
+	for _, c := range tu.Cases {
+		child := xml.StartElement{
+			Name: xml.Name{Local: "trans-unit"},
+			Attr: attrs("id", tu.ID+"["+c.Selector+"]", "xl:case", c.Selector),
+		}
+		if err := e.EncodeToken(child); err != nil {
+			return err
+		}
+		if err := e.EncodeElement(c.Source, xml.StartElement{Name: xml.Name{Local: "source"}}); err != nil {
+			return err
+		}
+		if err := e.EncodeElement(c.Target, xml.StartElement{Name: xml.Name{Local: "target"}}); err != nil {
+			return err
+		}
+		if tu.Note != "" {
+			if err := e.EncodeElement(tu.Note, xml.StartElement{Name: xml.Name{Local: "note"}}); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(child.End()); err != nil {
+			return err
+		}
 	}
+	return e.EncodeToken(group.End())
+}
 
-	return &document, nil
+// UnmarshalXML implements xml.Unmarshaler, reading both plain
+// <trans-unit> elements and x-gettext-plurals <group> elements back
+// into TransUnits.
+func (b *Body) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "trans-unit":
+				var tu TransUnit
+				if err := d.DecodeElement(&tu, &t); err != nil {
+					return err
+				}
+				b.TransUnits = append(b.TransUnits, tu)
+			case "group":
+				if attrValue(t, "restype") == "x-gettext-plurals" {
+					tu, err := unmarshalPluralGroup(d, t)
+					if err != nil {
+						return err
+					}
+					b.TransUnits = append(b.TransUnits, tu)
+				} else if err := d.Skip(); err != nil {
+					return err
+				}
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if t.Name == start.Name {
+				return nil
+			}
+		}
+	}
 }
 
-// Writes XLIFF Document to disk
-func (d *Document) ToFile(path string) error {
-	xliff := &DocumentExport{
-		Document:       d,
-		Xmlns:          "urn:oasis:names:tc:xliff:document:1.2",
-		Xsi:            "http://www.w3.org/2001/XMLSchema-instance",
-		SchemaLocation: "urn:oasis:names:tc:xliff:document:1.2 http://docs.oasis-open.org/xliff/v1.2/os/xliff-core-1.2-strict.xsd",
+func unmarshalPluralGroup(d *xml.Decoder, start xml.StartElement) (TransUnit, error) {
+	tu := TransUnit{
+		Approved:  YesNoAttr(attrValue(start, "approved")),
+		Translate: YesNoAttr(attrValue(start, "translate")),
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return tu, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "alt-trans" {
+				var alt AltTrans
+				if err := d.DecodeElement(&alt, &t); err != nil {
+					return tu, err
+				}
+				tu.AltTrans = append(tu.AltTrans, alt)
+				continue
+			}
+			if t.Name.Local != "trans-unit" {
+				if err := d.Skip(); err != nil {
+					return tu, err
+				}
+				continue
+			}
+			var child struct {
+				ID     string `xml:"id,attr"`
+				Case   string `xml:"case,attr"`
+				Source string `xml:"source"`
+				Target string `xml:"target"`
+				Note   string `xml:"note"`
+			}
+			if err := d.DecodeElement(&child, &t); err != nil {
+				return tu, err
+			}
+			selector := child.Case
+			if selector == "" {
+				selector = pluralSelectorFromID(child.ID)
+			}
+			if tu.ID == "" {
+				tu.ID = basePluralID(child.ID)
+				tu.Note = child.Note
+			}
+			tu.Cases = append(tu.Cases, Case{Selector: selector, Source: child.Source, Target: child.Target})
+		case xml.EndElement:
+			if t.Name == start.Name {
+				return tu, nil
+			}
+		}
 	}
+}
 
-	data, err := xml.Marshal(xliff)
-	if err != nil {
-		return err
+// basePluralID strips a "[selector]" suffix added by marshalPluralGroup
+// from a child trans-unit id, recovering the owning TransUnit's id.
+func basePluralID(id string) string {
+	if i := strings.LastIndexByte(id, '['); i >= 0 && strings.HasSuffix(id, "]") {
+		return id[:i]
 	}
-	data = []byte(xml.Header + string(data))
+	return id
+}
 
-	err = ioutil.WriteFile(path, data, 0664)
-	if err != nil {
-		return err
+// pluralSelectorFromID recovers the selector from a "[selector]" suffix
+// when a child trans-unit's xl:case attribute was stripped by a tool
+// that doesn't understand the extension namespace.
+func pluralSelectorFromID(id string) string {
+	if i := strings.LastIndexByte(id, '['); i >= 0 && strings.HasSuffix(id, "]") {
+		return id[i+1 : len(id)-1]
 	}
+	return ""
+}
 
-	return nil
+// Returns a new, empty xliff file.
+// datatype will always be "plaintext" and version will always be "1.2"
+func NewDocument(sl string, tl string) *Document {
+	file := File{
+		Datatype:       "plaintext",
+		SourceLanguage: sl,
+		TargetLanguage: tl,
+		Header:         Header{},
+		Body:           Body{},
+	}
+	return &Document{
+		Version: "1.2",
+		Files:   []File{file},
+	}
 }
 
+// FromFile and (*Document).ToFile live in stream.go, built on top of
+// Decoder and Encoder.
+
 // Returns true if the document passes some basic consistency checks.
 func (d *Document) Validate() []ValidationError {
 	var errors []ValidationError
@@ -173,32 +318,109 @@ func (d *Document) Validate() []ValidationError {
 						idx, file.Original),
 				})
 			}
-			if transUnit.Source == "" {
-				errors = append(errors, ValidationError{
-					Code: MissingTransUnitSource,
-					Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'source' attribute",
-						transUnit.ID, file.Original),
-				})
-			}
-			if transUnit.Target == "" {
-				errors = append(errors, ValidationError{
-					Code: MissingTransUnitTarget,
-					Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'target' attribute",
-						transUnit.ID, file.Original),
-				})
+			if len(transUnit.Cases) > 0 {
+				errors = append(errors, validatePluralCases(transUnit, file)...)
+			} else {
+				if transUnit.Source.IsEmpty() {
+					errors = append(errors, ValidationError{
+						Code: MissingTransUnitSource,
+						Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'source' attribute",
+							transUnit.ID, file.Original),
+					})
+				}
+				if transUnit.Target.IsEmpty() {
+					errors = append(errors, ValidationError{
+						Code: MissingTransUnitTarget,
+						Message: fmt.Sprintf("Translation unit '%s' in file '%s' is missing 'target' attribute",
+							transUnit.ID, file.Original),
+					})
+				}
+				errors = append(errors, validateInlineContent(transUnit, file, "source", transUnit.Source)...)
+				errors = append(errors, validateInlineContent(transUnit, file, "target", transUnit.Target.Content)...)
 			}
+			errors = append(errors, validateWorkflow(transUnit, file)...)
 		}
 	}
 
 	return errors
 }
 
+// validateInlineContent checks c, the Source or Target of transUnit
+// (identified by elem, "source" or "target"), for inline markup that
+// cannot round-trip: duplicate or missing ids, and paired tags whose
+// partner was never found.
+func validateInlineContent(transUnit TransUnit, file File, elem string, c Content) []ValidationError {
+	var errs []ValidationError
+	ids := map[string]int{}
+
+	var walk func([]Run)
+	walk = func(runs []Run) {
+		for _, r := range runs {
+			switch v := r.(type) {
+			case Placeholder:
+				ids[v.ID]++
+			case Group:
+				ids[v.ID]++
+				walk(v.Children)
+			case PairedTag:
+				ids[v.ID]++
+				if v.Open == "" || v.Close == "" {
+					errs = append(errs, ValidationError{
+						Code: UnbalancedPairedTag,
+						Message: fmt.Sprintf("Translation unit '%s' in file '%s' has an unbalanced paired tag '%s' in <%s>",
+							transUnit.ID, file.Original, v.ID, elem),
+					})
+				}
+				walk(v.Children)
+			}
+		}
+	}
+	walk(c.Runs)
+
+	for id, count := range ids {
+		if id == "" {
+			errs = append(errs, ValidationError{
+				Code: MissingInlineID,
+				Message: fmt.Sprintf("Translation unit '%s' in file '%s' has an inline element missing an 'id' attribute in <%s>",
+					transUnit.ID, file.Original, elem),
+			})
+			continue
+		}
+		if count > 1 {
+			errs = append(errs, ValidationError{
+				Code: DuplicateInlineID,
+				Message: fmt.Sprintf("Translation unit '%s' in file '%s' reuses inline id '%s' in <%s>",
+					transUnit.ID, file.Original, id, elem),
+			})
+		}
+	}
+
+	return errs
+}
+
 // Returns true if all translation units in all files have both a
-// non-empty source and target.
+// non-empty source and target. A trans-unit carrying plural Cases must
+// instead have a translated Target for every CLDR plural category
+// required by the file's target language.
 func (d *Document) IsComplete() bool {
 	for _, file := range d.Files {
+		required := requiredPluralCategories(file.TargetLanguage)
 		for _, transUnit := range file.Body.TransUnits {
-			if transUnit.Source == "" || transUnit.Target == "" {
+			if len(transUnit.Cases) > 0 {
+				have := map[string]bool{}
+				for _, c := range transUnit.Cases {
+					if c.Target != "" {
+						have[c.Selector] = true
+					}
+				}
+				for _, category := range required {
+					if !have[category] {
+						return false
+					}
+				}
+				continue
+			}
+			if transUnit.Source.IsEmpty() || transUnit.Target.IsEmpty() {
 				return false
 			}
 		}
@@ -236,7 +458,7 @@ func (d *Document) AddTransUnit(source string, opts ...func(*TransUnit)) error {
 
 	tu := TransUnit{
 		ID:     strconv.Itoa(numId + 1),
-		Source: source,
+		Source: TextContent(source),
 	}
 
 	for _, opt := range opts {
@@ -276,6 +498,14 @@ const (
 	MissingTransUnitID
 	MissingTransUnitSource
 	MissingTransUnitTarget
+	MissingInlineID
+	DuplicateInlineID
+	UnbalancedPairedTag
+	MissingPluralCategory
+	UnknownPluralSelector
+	StreamDecodeError
+	UnknownTargetState
+	IllegalStateTransition
 )
 
 type ValidationError struct {
@@ -306,15 +536,48 @@ func (ve ValidationError) Error() string {
 		code = "MissingTransUnitSource"
 	case MissingTransUnitTarget:
 		code = "MissingTransUnitTarget"
+	case MissingInlineID:
+		code = "MissingInlineID"
+	case DuplicateInlineID:
+		code = "DuplicateInlineID"
+	case UnbalancedPairedTag:
+		code = "UnbalancedPairedTag"
+	case MissingPluralCategory:
+		code = "MissingPluralCategory"
+	case UnknownPluralSelector:
+		code = "UnknownPluralSelector"
+	case StreamDecodeError:
+		code = "StreamDecodeError"
+	case UnknownTargetState:
+		code = "UnknownTargetState"
+	case IllegalStateTransition:
+		code = "IllegalStateTransition"
 	}
 	return fmt.Sprintf("%s: %s", code, ve.Message)
 }
 
 type TransUnit struct {
-	ID     string `xml:"id,attr"`
-	Source string `xml:"source"`
-	Target string `xml:"target"`
-	Note   string `xml:"note"`
+	ID        string     `xml:"id,attr"`
+	Approved  YesNoAttr  `xml:"approved,attr,omitempty"`
+	Translate YesNoAttr  `xml:"translate,attr,omitempty"`
+	Source    Content    `xml:"source"`
+	Target    Target     `xml:"target"`
+	Note      string     `xml:"note"`
+	AltTrans  []AltTrans `xml:"alt-trans"`
+
+	// Cases holds this unit's ICU-style plural/select variants, keyed
+	// by CLDR plural category. A TransUnit with a non-empty Cases
+	// marshals as a <group restype="x-gettext-plurals"> instead of a
+	// plain <trans-unit>, and its own Source/Target are unused.
+	Cases []Case
+}
+
+// Case is one plural/select variant of a TransUnit, e.g. the "one" or
+// "other" category of an English count.
+type Case struct {
+	Selector string
+	Source   string
+	Target   string
 }
 
 func WithNote(note string) func(*TransUnit) {
@@ -325,6 +588,6 @@ func WithNote(note string) func(*TransUnit) {
 
 func WithTarget(target string) func(*TransUnit) {
 	return func(t *TransUnit) {
-		t.Target = target
+		t.Target = Target{Content: TextContent(target)}
 	}
 }
@@ -214,15 +214,15 @@ func Test_CreateXLIFF(t *testing.T) {
 
 	tu := xliff.TransUnit{
 		ID:     "0",
-		Source: "Hallo Welt",
-		Target: "Hello World",
+		Source: xliff.TextContent("Hallo Welt"),
+		Target: xliff.Target{Content: xliff.TextContent("Hello World")},
 		Note:   "Some Comment",
 	}
 
 	tu2 := xliff.TransUnit{
 		ID:     "1",
-		Source: "Auf Wiedersehen, Welt",
-		Target: "Goodbye World",
+		Source: xliff.TextContent("Auf Wiedersehen, Welt"),
+		Target: xliff.Target{Content: xliff.TextContent("Goodbye World")},
 		Note:   "Some Comment",
 	}
 